@@ -0,0 +1,22 @@
+package matrixfederation
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// CanonicalJSON re-encodes the given JSON object using the encoding used by
+// the Matrix specification: no insignificant whitespace and lexicographically
+// sorted object keys. encoding/json already sorts map keys when marshalling,
+// so round-tripping the input through generic maps gives us a canonical
+// encoding at every nesting level. Numbers are kept as json.Number so that
+// their text is not changed by the round trip.
+func CanonicalJSON(input []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	return json.Marshal(object)
+}
@@ -0,0 +1,92 @@
+package matrixfederation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/crypto/ed25519"
+)
+
+// canonicalCBOREncMode encodes map keys in the sorted order required by
+// RFC 7049's "Canonical CBOR", so that SignCBOR produces a deterministic
+// encoding for a given document.
+var canonicalCBOREncMode = mustCanonicalCBOREncMode()
+
+func mustCanonicalCBOREncMode() cbor.EncMode {
+	mode, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		panic(fmt.Sprintf("gomatrixserverlib: invalid canonical CBOR encoding options: %v", err))
+	}
+	return mode
+}
+
+// MarshalCBOR implements cbor.Marshaler, encoding the bytes as a CBOR byte
+// string rather than base64 text.
+func (b Base64String) MarshalCBOR() ([]byte, error) {
+	return canonicalCBOREncMode.Marshal([]byte(b))
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler.
+func (b *Base64String) UnmarshalCBOR(data []byte) error {
+	var raw []byte
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = Base64String(raw)
+	return nil
+}
+
+// cborToJSON re-encodes a CBOR document as the equivalent JSON, so that it
+// can be run through the ordinary canonical-JSON signing path. CBOR byte
+// strings become base64 text, matching how Base64String is represented in
+// JSON.
+func cborToJSON(cborBytes []byte) ([]byte, error) {
+	var tree interface{}
+	if err := cbor.Unmarshal(cborBytes, &tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalizeGenericValue(tree))
+}
+
+// jsonToCBOR re-encodes a JSON document as the equivalent deterministic
+// CBOR. Numbers are decoded via decodeJSONGeneric so that integers outside
+// float64's 53-bit mantissa survive the round trip.
+func jsonToCBOR(jsonBytes []byte) ([]byte, error) {
+	tree, err := decodeJSONGeneric(jsonBytes)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalCBOREncMode.Marshal(tree)
+}
+
+// SignCBOR signs a CBOR document the same way SignJSON signs a JSON one,
+// for constrained clients that want to sign and verify Matrix-style
+// documents without depending on a JSON encoder. The document is converted
+// to canonical JSON, signed there, and the result converted back to
+// deterministic CBOR, so the signature is identical to the one SignJSON
+// would have produced for the logical equivalent JSON document. Integers are
+// preserved exactly as long as they fit in an int64; anything wider
+// round-trips through float64 like any other JSON number outside that
+// range.
+func SignCBOR(signingName, keyID string, privateKey ed25519.PrivateKey, message []byte) ([]byte, error) {
+	jsonMessage, err := cborToJSON(message)
+	if err != nil {
+		return nil, fmt.Errorf("gomatrixserverlib: could not convert CBOR to JSON: %w", err)
+	}
+	signed, err := SignJSON(signingName, keyID, privateKey, jsonMessage)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToCBOR(signed)
+}
+
+// VerifyCBOR checks a CBOR document's signature the same way VerifyJSON
+// checks a JSON one, by converting it to its canonical JSON form first.
+func VerifyCBOR(signingName, keyID string, publicKey ed25519.PublicKey, message []byte) error {
+	jsonMessage, err := cborToJSON(message)
+	if err != nil {
+		return fmt.Errorf("gomatrixserverlib: could not convert CBOR to JSON: %w", err)
+	}
+	return VerifyJSON(signingName, keyID, publicKey, jsonMessage)
+}
@@ -0,0 +1,83 @@
+package matrixfederation
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestSignAndVerifyCBOR(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	entityName := "example.com"
+	keyID := "ed25519:my_key_id"
+	input, err := canonicalCBOREncMode.Marshal(map[string]interface{}{"this": "is", "my": "message"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignCBOR(entityName, keyID, privateKey, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyCBOR(entityName, keyID, publicKey, signed); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonForm, err := cborToJSON(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyJSON(entityName, keyID, publicKey, jsonForm); err != nil {
+		t.Fatalf("CBOR signature did not verify as JSON: %v", err)
+	}
+}
+
+func TestSignAndVerifyCBORLargeInteger(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	entityName := "example.com"
+	keyID := "ed25519:my_key_id"
+	// 2^53 + 1: the smallest integer a float64 cannot represent exactly.
+	input, err := canonicalCBOREncMode.Marshal(map[string]interface{}{"big": int64(9007199254740993)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignCBOR(entityName, keyID, privateKey, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyCBOR(entityName, keyID, publicKey, signed); err != nil {
+		t.Fatalf("VerifyCBOR failed for a freshly signed document: %v", err)
+	}
+}
+
+func TestBase64StringCBORRoundTrip(t *testing.T) {
+	want := Base64String("some signature bytes")
+
+	encoded, err := cbor.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Base64String
+	if err := cbor.Unmarshal(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Base64String CBOR round-trip = %q, want %q", got, want)
+	}
+}
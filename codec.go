@@ -0,0 +1,84 @@
+package matrixfederation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// normalizeGenericValue recursively converts a value decoded generically
+// from YAML or CBOR into one that encoding/json can marshal: map keys are
+// converted to strings and byte strings are base64-encoded, matching the
+// representation canonical JSON uses for the same document.
+func normalizeGenericValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			m[fmt.Sprintf("%v", k)] = normalizeGenericValue(v)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			m[k] = normalizeGenericValue(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(val))
+		for i, elem := range val {
+			s[i] = normalizeGenericValue(elem)
+		}
+		return s
+	case []byte:
+		return Base64String(val).Encode()
+	default:
+		return v
+	}
+}
+
+// decodeJSONGeneric unmarshals jsonBytes into a generic tree of
+// map[string]interface{}, []interface{} and scalars, the same shape
+// normalizeGenericValue produces from YAML/CBOR. Unlike a plain
+// json.Unmarshal into interface{}, it decodes numbers via json.Number and
+// converts ones written as integers to int64 rather than collapsing everything to
+// float64, so that integers outside float64's 53-bit mantissa still
+// round-trip exactly through a YAML or CBOR re-encoding.
+func decodeJSONGeneric(jsonBytes []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return normalizeJSONNumbers(tree), nil
+}
+
+// normalizeJSONNumbers recursively replaces the json.Number leaves left by a
+// UseNumber decode with an int64, if the number is written as an integer
+// (no fraction or exponent) and fits one, or a float64 otherwise.
+func normalizeJSONNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			m[k] = normalizeJSONNumbers(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(val))
+		for i, elem := range val {
+			s[i] = normalizeJSONNumbers(elem)
+		}
+		return s
+	default:
+		return v
+	}
+}
@@ -0,0 +1,210 @@
+package cose
+
+import (
+	"fmt"
+	"sort"
+)
+
+// This file implements just enough of RFC 7049 CBOR to build and parse the
+// COSE_Sign1 structures used by SignCOSE/VerifyCOSE: unsigned/negative
+// integers, byte strings, text strings, arrays and maps keyed by small
+// integers. It intentionally does not attempt to be a general purpose CBOR
+// codec.
+
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+)
+
+// appendHead appends the CBOR initial bytes for the given major type and
+// argument value.
+func appendHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n < 1<<8:
+		return append(buf, major<<5|24, byte(n))
+	case n < 1<<16:
+		return append(buf, major<<5|25, byte(n>>8), byte(n))
+	case n < 1<<32:
+		return append(buf, major<<5|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		return append(buf, major<<5|27,
+			byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+			byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+// encodeBytes CBOR-encodes a byte string.
+func encodeBytes(b []byte) []byte {
+	buf := appendHead(nil, majorBytes, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// encodeText CBOR-encodes a UTF-8 text string.
+func encodeText(s string) []byte {
+	buf := appendHead(nil, majorText, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// encodeInt CBOR-encodes a signed integer.
+func encodeInt(n int64) []byte {
+	if n >= 0 {
+		return appendHead(nil, majorUint, uint64(n))
+	}
+	return appendHead(nil, majorNegInt, uint64(-1-n))
+}
+
+// encodeIntMap CBOR-encodes a map whose keys are small integers, in
+// ascending key order so that the encoding is deterministic.
+func encodeIntMap(m map[int]interface{}) ([]byte, error) {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	buf := appendHead(nil, majorMap, uint64(len(keys)))
+	for _, k := range keys {
+		buf = append(buf, encodeInt(int64(k))...)
+		encoded, err := encodeValue(m[k])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, encoded...)
+	}
+	return buf, nil
+}
+
+// encodeValue CBOR-encodes one of the value types that can appear in a COSE
+// protected header map.
+func encodeValue(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case int:
+		return encodeInt(int64(val)), nil
+	case int64:
+		return encodeInt(val), nil
+	case string:
+		return encodeText(val), nil
+	case []byte:
+		return encodeBytes(val), nil
+	default:
+		return nil, fmt.Errorf("cose: cannot CBOR-encode value of type %T", v)
+	}
+}
+
+// head reads a CBOR initial byte sequence, returning the major type, the
+// argument value and the remainder of data after the head.
+func head(data []byte) (major byte, value uint64, rest []byte, err error) {
+	if len(data) == 0 {
+		return 0, 0, nil, fmt.Errorf("cose: unexpected end of CBOR data")
+	}
+	major = data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	switch {
+	case info < 24:
+		return major, uint64(info), data, nil
+	case info == 24:
+		if len(data) < 1 {
+			return 0, 0, nil, fmt.Errorf("cose: truncated CBOR head")
+		}
+		return major, uint64(data[0]), data[1:], nil
+	case info == 25:
+		if len(data) < 2 {
+			return 0, 0, nil, fmt.Errorf("cose: truncated CBOR head")
+		}
+		return major, uint64(data[0])<<8 | uint64(data[1]), data[2:], nil
+	case info == 26:
+		if len(data) < 4 {
+			return 0, 0, nil, fmt.Errorf("cose: truncated CBOR head")
+		}
+		v := uint64(data[0])<<24 | uint64(data[1])<<16 | uint64(data[2])<<8 | uint64(data[3])
+		return major, v, data[4:], nil
+	case info == 27:
+		if len(data) < 8 {
+			return 0, 0, nil, fmt.Errorf("cose: truncated CBOR head")
+		}
+		var v uint64
+		for i := 0; i < 8; i++ {
+			v = v<<8 | uint64(data[i])
+		}
+		return major, v, data[8:], nil
+	default:
+		return 0, 0, nil, fmt.Errorf("cose: unsupported CBOR additional info %d", info)
+	}
+}
+
+// decodeBytes reads a CBOR byte string, returning its content and the
+// remaining data.
+func decodeBytes(data []byte) (content, rest []byte, err error) {
+	major, n, rest, err := head(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if major != majorBytes {
+		return nil, nil, fmt.Errorf("cose: expected CBOR byte string, got major type %d", major)
+	}
+	if uint64(len(rest)) < n {
+		return nil, nil, fmt.Errorf("cose: truncated CBOR byte string")
+	}
+	return rest[:n], rest[n:], nil
+}
+
+// decodeArrayHeader reads a CBOR array head, returning the declared number
+// of elements and the remaining data.
+func decodeArrayHeader(data []byte) (n uint64, rest []byte, err error) {
+	major, n, rest, err := head(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	if major != majorArray {
+		return 0, nil, fmt.Errorf("cose: expected CBOR array, got major type %d", major)
+	}
+	return n, rest, nil
+}
+
+// skipValue skips over a single, possibly nested, CBOR value and returns
+// what follows it. It is used to skip the COSE unprotected header map and
+// the protected header values other than alg.
+func skipValue(data []byte) (rest []byte, err error) {
+	major, n, rest, err := head(data)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint, majorNegInt:
+		return rest, nil
+	case majorBytes, majorText:
+		if uint64(len(rest)) < n {
+			return nil, fmt.Errorf("cose: truncated CBOR string")
+		}
+		return rest[n:], nil
+	case majorArray:
+		for i := uint64(0); i < n; i++ {
+			rest, err = skipValue(rest)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return rest, nil
+	case majorMap:
+		for i := uint64(0); i < n; i++ {
+			if rest, err = skipValue(rest); err != nil {
+				return nil, err
+			}
+			if rest, err = skipValue(rest); err != nil {
+				return nil, err
+			}
+		}
+		return rest, nil
+	default:
+		return nil, fmt.Errorf("cose: cannot skip CBOR major type %d", major)
+	}
+}
@@ -0,0 +1,181 @@
+// Package cose encodes and decodes Matrix events and server-key documents as
+// COSE_Sign1 structures (RFC 8152), so that constrained federation clients
+// can verify them over a compact binary transport without a JSON
+// canonicaliser. It reuses the ed25519 keys that matrixfederation.VerifyJSON
+// already accepts.
+package cose
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// algEdDSA is the COSE algorithm identifier for EdDSA (RFC 8152 Table 5).
+const algEdDSA = -8
+
+// COSE header parameter labels (RFC 8152 Table 2).
+const (
+	headerAlg = 1
+	headerKid = 4
+)
+
+// cose1Tag is the CBOR tag number for COSE_Sign1 (RFC 8152 Section 2).
+const cose1Tag = 18
+
+// sig1Context is the context string used in the Sig_structure for a
+// COSE_Sign1 object (RFC 8152 Section 4.4).
+const sig1Context = "Signature1"
+
+// SignCOSE wraps payload in a COSE_Sign1 structure, signed with EdDSA using
+// priv. The protected header always contains alg (EdDSA) and kid (keyID);
+// any entries in extProtected are merged in alongside them.
+func SignCOSE(entity, keyID string, priv ed25519.PrivateKey, payload []byte, extProtected map[int]interface{}) ([]byte, error) {
+	protectedMap := map[int]interface{}{
+		headerAlg: algEdDSA,
+		headerKid: []byte(keyID),
+	}
+	for k, v := range extProtected {
+		protectedMap[k] = v
+	}
+
+	protectedMapBytes, err := encodeIntMap(protectedMap)
+	if err != nil {
+		return nil, err
+	}
+	protected := encodeBytes(protectedMapBytes)
+
+	externalAAD := encodeBytes(nil)
+	payloadBstr := encodeBytes(payload)
+
+	toBeSigned := appendHead(nil, majorArray, 4)
+	toBeSigned = append(toBeSigned, encodeText(sig1Context)...)
+	toBeSigned = append(toBeSigned, protected...)
+	toBeSigned = append(toBeSigned, externalAAD...)
+	toBeSigned = append(toBeSigned, payloadBstr...)
+
+	signature := ed25519.Sign(priv, toBeSigned)
+
+	result := appendHead(nil, majorTag, cose1Tag)
+	result = appendHead(result, majorArray, 4)
+	result = append(result, protected...)
+	result = append(result, appendHead(nil, majorMap, 0)...) // empty unprotected header
+	result = append(result, payloadBstr...)
+	result = append(result, encodeBytes(signature)...)
+
+	return result, nil
+}
+
+// VerifyCOSE checks the EdDSA signature on a COSE_Sign1 structure against
+// pub and, if it is valid, returns the enclosed payload.
+func VerifyCOSE(pub ed25519.PublicKey, data []byte) ([]byte, error) {
+	major, tag, rest, err := head(data)
+	if err != nil {
+		return nil, err
+	}
+	if major != majorTag || tag != cose1Tag {
+		return nil, fmt.Errorf("cose: expected COSE_Sign1 tag %d, got major type %d tag %d", cose1Tag, major, tag)
+	}
+
+	n, rest, err := decodeArrayHeader(rest)
+	if err != nil {
+		return nil, err
+	}
+	if n != 4 {
+		return nil, fmt.Errorf("cose: expected COSE_Sign1 array of 4 elements, got %d", n)
+	}
+
+	protectedStart := rest
+	protectedMapBytes, rest, err := decodeBytes(rest)
+	if err != nil {
+		return nil, err
+	}
+	protected := protectedStart[:len(protectedStart)-len(rest)]
+
+	if err = checkProtectedAlg(protectedMapBytes); err != nil {
+		return nil, err
+	}
+
+	if rest, err = skipValue(rest); err != nil { // unprotected header
+		return nil, err
+	}
+
+	payload, rest, err := decodeBytes(rest)
+	if err != nil {
+		return nil, err
+	}
+	payloadBstr := encodeBytes(payload)
+
+	signature, _, err := decodeBytes(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	externalAAD := encodeBytes(nil)
+	toBeSigned := appendHead(nil, majorArray, 4)
+	toBeSigned = append(toBeSigned, encodeText(sig1Context)...)
+	toBeSigned = append(toBeSigned, protected...)
+	toBeSigned = append(toBeSigned, externalAAD...)
+	toBeSigned = append(toBeSigned, payloadBstr...)
+
+	if !ed25519.Verify(pub, toBeSigned, signature) {
+		return nil, fmt.Errorf("cose: invalid signature")
+	}
+	return payload, nil
+}
+
+// checkProtectedAlg decodes a protected header map and confirms that it
+// declares the EdDSA algorithm.
+func checkProtectedAlg(mapBytes []byte) error {
+	major, count, rest, err := head(mapBytes)
+	if err != nil {
+		return err
+	}
+	if major != majorMap {
+		return fmt.Errorf("cose: protected header is not a CBOR map")
+	}
+
+	foundAlg := false
+	for i := uint64(0); i < count; i++ {
+		var key int64
+		var keyMajor byte
+		var keyVal uint64
+		keyMajor, keyVal, rest, err = head(rest)
+		if err != nil {
+			return err
+		}
+		if keyMajor == majorNegInt {
+			key = -1 - int64(keyVal)
+		} else {
+			key = int64(keyVal)
+		}
+
+		if key != headerAlg {
+			if rest, err = skipValue(rest); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var algMajor byte
+		var algVal uint64
+		algMajor, algVal, rest, err = head(rest)
+		if err != nil {
+			return err
+		}
+		var alg int64
+		if algMajor == majorNegInt {
+			alg = -1 - int64(algVal)
+		} else {
+			alg = int64(algVal)
+		}
+		if alg != algEdDSA {
+			return fmt.Errorf("cose: unsupported COSE algorithm %d, expected EdDSA (%d)", alg, algEdDSA)
+		}
+		foundAlg = true
+	}
+	if !foundAlg {
+		return fmt.Errorf("cose: protected header does not declare an algorithm")
+	}
+	return nil
+}
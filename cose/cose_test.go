@@ -0,0 +1,52 @@
+package cose
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestSignAndVerifyCOSE(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"this":"is","my":"event"}`)
+	signed, err := SignCOSE("example.com", "ed25519:1", privateKey, payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := VerifyCOSE(publicKey, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("VerifyCOSE payload = %q, want %q", got, payload)
+	}
+}
+
+func TestVerifyCOSERejectsModifiedPayload(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignCOSE("example.com", "ed25519:1", privateKey, []byte("original"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip a byte inside the payload bstr content. The encoding ends with the
+	// 66-byte signature bstr (a 2-byte head and 64 bytes of signature), so the
+	// last payload byte sits just before it.
+	signed[len(signed)-66-1] ^= 0xff
+
+	if _, err := VerifyCOSE(publicKey, signed); err == nil {
+		t.Fatal("expected VerifyCOSE to reject a modified payload")
+	}
+}
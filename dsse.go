@@ -0,0 +1,137 @@
+package matrixfederation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// dssePAEPrefix is the version string used by the DSSE pre-authentication
+// encoding (PAE).
+const dssePAEPrefix = "DSSEv1"
+
+// Envelope is a Dead Simple Signing Envelope as defined by
+// https://github.com/secure-systems-lab/dsse. It lets a payload of any
+// content type be signed with the same ed25519 keys that VerifyJSON uses for
+// Matrix events, so that non-JSON payloads (media, blobs) can be carried
+// between servers with the same trust model.
+type Envelope struct {
+	Payload     Base64String        `json:"payload"`
+	PayloadType string              `json:"payloadType"`
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+// EnvelopeSignature is a single signature within an Envelope.
+type EnvelopeSignature struct {
+	KeyID     string       `json:"keyid"`
+	Signature Base64String `json:"sig"`
+}
+
+// EnvelopeSigner pairs an entity name and key ID with the ed25519 private
+// key used to produce one of an Envelope's signatures.
+type EnvelopeSigner struct {
+	Entity     string
+	KeyID      string
+	PrivateKey ed25519.PrivateKey
+}
+
+// EnvelopeVerifyKey is a trusted key that VerifyEnvelope will check an
+// Envelope's signatures against.
+type EnvelopeVerifyKey struct {
+	KeyID     string
+	PublicKey ed25519.PublicKey
+}
+
+// preAuthEncode computes the DSSE pre-authentication encoding of a payload
+// type and payload:
+//
+//	"DSSEv1" SP len(type) SP type SP len(payload) SP payload
+//
+// where SP is a single ASCII space and lengths are the decimal ASCII
+// encoding of the number of bytes.
+func preAuthEncode(payloadType string, payload []byte) []byte {
+	result := make([]byte, 0, len(dssePAEPrefix)+len(payloadType)+len(payload)+32)
+	result = append(result, dssePAEPrefix...)
+	result = append(result, ' ')
+	result = append(result, strconv.Itoa(len(payloadType))...)
+	result = append(result, ' ')
+	result = append(result, payloadType...)
+	result = append(result, ' ')
+	result = append(result, strconv.Itoa(len(payload))...)
+	result = append(result, ' ')
+	result = append(result, payload...)
+	return result
+}
+
+// SignEnvelope wraps payload in a DSSE envelope of the given payloadType,
+// signed by a single keyID.
+func SignEnvelope(payloadType string, payload []byte, keyID string, privateKey ed25519.PrivateKey) (Envelope, error) {
+	return SignEnvelopeMulti(payloadType, payload, []EnvelopeSigner{{KeyID: keyID, PrivateKey: privateKey}})
+}
+
+// SignEnvelopeMulti wraps payload in a DSSE envelope signed by every signer
+// provided, so that multiple notaries can co-sign the same payload.
+func SignEnvelopeMulti(payloadType string, payload []byte, signers []EnvelopeSigner) (Envelope, error) {
+	if len(signers) == 0 {
+		return Envelope{}, fmt.Errorf("gomatrixserverlib: no signers given for envelope")
+	}
+
+	pae := preAuthEncode(payloadType, payload)
+
+	signatures := make([]EnvelopeSignature, len(signers))
+	for i, signer := range signers {
+		signatures[i] = EnvelopeSignature{
+			KeyID:     signer.KeyID,
+			Signature: Base64String(ed25519.Sign(signer.PrivateKey, pae)),
+		}
+	}
+
+	return Envelope{
+		Payload:     Base64String(payload),
+		PayloadType: payloadType,
+		Signatures:  signatures,
+	}, nil
+}
+
+// VerifyEnvelope checks that at least threshold of the trusted keys have a
+// valid signature over the envelope, and returns the key IDs that verified.
+func VerifyEnvelope(envelope Envelope, threshold int, keys []EnvelopeVerifyKey) ([]string, error) {
+	pae := preAuthEncode(envelope.PayloadType, []byte(envelope.Payload))
+
+	trusted := make(map[string]ed25519.PublicKey, len(keys))
+	for _, key := range keys {
+		trusted[key.KeyID] = key.PublicKey
+	}
+
+	seen := make(map[string]bool, len(keys))
+	var verified []string
+	for _, sig := range envelope.Signatures {
+		publicKey, ok := trusted[sig.KeyID]
+		if !ok || seen[sig.KeyID] {
+			continue
+		}
+		if ed25519.Verify(publicKey, pae, []byte(sig.Signature)) {
+			seen[sig.KeyID] = true
+			verified = append(verified, sig.KeyID)
+		}
+	}
+
+	if len(verified) < threshold {
+		return verified, fmt.Errorf("gomatrixserverlib: envelope has %d valid signatures, need at least %d", len(verified), threshold)
+	}
+	return verified, nil
+}
+
+// MarshalEnvelope encodes an Envelope as its JSON wire format.
+func MarshalEnvelope(envelope Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+// UnmarshalEnvelope decodes an Envelope from its JSON wire format.
+func UnmarshalEnvelope(data []byte) (Envelope, error) {
+	var envelope Envelope
+	err := json.Unmarshal(data, &envelope)
+	return envelope, err
+}
@@ -0,0 +1,102 @@
+package matrixfederation
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestSignAndVerifyEnvelope(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("this is not JSON, it's a blob")
+	envelope, err := SignEnvelope("application/vnd.matrix+json", payload, "ed25519:1", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal([]byte(envelope.Payload), payload) {
+		t.Fatalf("envelope payload = %q, want %q", envelope.Payload, payload)
+	}
+
+	verified, err := VerifyEnvelope(envelope, 1, []EnvelopeVerifyKey{{KeyID: "ed25519:1", PublicKey: publicKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verified) != 1 || verified[0] != "ed25519:1" {
+		t.Fatalf("VerifyEnvelope verified = %v, want [ed25519:1]", verified)
+	}
+}
+
+func TestVerifyEnvelopeThreshold(t *testing.T) {
+	publicKey1, privateKey1, err := ed25519.GenerateKey(bytes.NewBuffer([]byte("Some 32 randomly generated bytes")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey2, privateKey2, err := ed25519.GenerateKey(bytes.NewBuffer([]byte("Some other 32 random bytes, too!")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("blob contents")
+	signers := []EnvelopeSigner{
+		{KeyID: "ed25519:1", PrivateKey: privateKey1},
+		{KeyID: "ed25519:2", PrivateKey: privateKey2},
+	}
+	envelope, err := SignEnvelopeMulti("application/octet-stream", payload, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []EnvelopeVerifyKey{
+		{KeyID: "ed25519:1", PublicKey: publicKey1},
+		{KeyID: "ed25519:2", PublicKey: publicKey2},
+	}
+
+	if _, err := VerifyEnvelope(envelope, 2, keys); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyEnvelope(envelope, 3, keys); err == nil {
+		t.Fatal("expected VerifyEnvelope to fail when threshold exceeds available signatures")
+	}
+}
+
+func TestVerifyEnvelopeRejectsDuplicateSignature(t *testing.T) {
+	publicKey1, privateKey1, err := ed25519.GenerateKey(bytes.NewBuffer([]byte("Some 32 randomly generated bytes")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte("blob contents")
+	envelope, err := SignEnvelope("application/octet-stream", payload, "ed25519:1", privateKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An attacker controls envelope.Signatures, so duplicating a single
+	// valid signature must not let it count twice towards the threshold.
+	envelope.Signatures = append(envelope.Signatures, envelope.Signatures[0])
+
+	keys := []EnvelopeVerifyKey{{KeyID: "ed25519:1", PublicKey: publicKey1}}
+
+	verified, err := VerifyEnvelope(envelope, 2, keys)
+	if err == nil {
+		t.Fatalf("expected VerifyEnvelope to fail when threshold 2 is met only by a duplicated signature, got verified = %v", verified)
+	}
+	if len(verified) != 1 {
+		t.Fatalf("VerifyEnvelope verified = %v, want exactly one distinct key ID", verified)
+	}
+}
+
+func TestPreAuthEncode(t *testing.T) {
+	got := preAuthEncode("http://example.com", []byte("hello"))
+	want := "DSSEv1 18 http://example.com 5 hello"
+	if string(got) != want {
+		t.Fatalf("preAuthEncode() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package matrixfederation
+
+import (
+	"crypto"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// A Signer produces signatures over arbitrary byte strings on behalf of a
+// single key. SignJSON uses a Signer to keep the private key material out of
+// the canonical-JSON signing path, so that a homeserver operator can keep
+// keys in an HSM or cloud KMS instead of on disk.
+type Signer interface {
+	// Sign returns a signature over msg.
+	Sign(msg []byte) ([]byte, error)
+	// KeyID identifies the key used to produce the signature, e.g.
+	// "ed25519:1".
+	KeyID() string
+	// Public returns the public key that corresponds to this signer.
+	Public() crypto.PublicKey
+}
+
+// ed25519Signer is the in-memory Signer used by SignJSON, backed by a raw
+// ed25519 private key held in process memory.
+type ed25519Signer struct {
+	keyID      string
+	privateKey ed25519.PrivateKey
+}
+
+// NewEd25519Signer returns a Signer that holds privateKey in memory.
+func NewEd25519Signer(keyID string, privateKey ed25519.PrivateKey) Signer {
+	return ed25519Signer{keyID: keyID, privateKey: privateKey}
+}
+
+func (s ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, msg), nil
+}
+
+func (s ed25519Signer) KeyID() string { return s.keyID }
+
+func (s ed25519Signer) Public() crypto.PublicKey { return s.privateKey.Public() }
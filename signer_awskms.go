@@ -0,0 +1,57 @@
+//go:build awskms
+// +build awskms
+
+package matrixfederation
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// awsKMSSigner is a Signer backed by an asymmetric signing key held in AWS
+// KMS. Build with the "awskms" tag to use it.
+//
+// As of this writing, AWS KMS does not offer Ed25519 as an asymmetric
+// signing key spec, so SigningAlgorithm "EDDSA" below will be rejected by
+// the service; NewAWSKMSSigner is provided against the day AWS adds support,
+// and is not usable against the real API yet.
+type awsKMSSigner struct {
+	keyID     string
+	kmsKeyID  string // ARN or key ID of the KMS key
+	client    *kms.KMS
+	publicKey crypto.PublicKey
+}
+
+// NewAWSKMSSigner returns a Signer for the asymmetric ED25519 KMS key
+// identified by kmsKeyID.
+func NewAWSKMSSigner(client *kms.KMS, keyID, kmsKeyID string) (Signer, error) {
+	resp, err := client.GetPublicKey(&kms.GetPublicKeyInput{KeyId: aws.String(kmsKeyID)})
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := parsePKIXPublicKey(resp.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &awsKMSSigner{keyID: keyID, kmsKeyID: kmsKeyID, client: client, publicKey: publicKey}, nil
+}
+
+func (s *awsKMSSigner) Sign(msg []byte) ([]byte, error) {
+	resp, err := s.client.Sign(&kms.SignInput{
+		KeyId:            aws.String(s.kmsKeyID),
+		Message:          msg,
+		MessageType:      aws.String(kms.MessageTypeRaw),
+		SigningAlgorithm: aws.String("EDDSA"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gomatrixserverlib: AWS KMS Sign: %w", err)
+	}
+	return resp.Signature, nil
+}
+
+func (s *awsKMSSigner) KeyID() string { return s.keyID }
+
+func (s *awsKMSSigner) Public() crypto.PublicKey { return s.publicKey }
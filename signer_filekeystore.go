@@ -0,0 +1,106 @@
+package matrixfederation
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// fileKeystoreSaltSize and fileKeystoreNonceSize are the sizes used when
+// encrypting a private key to disk: a per-file scrypt salt and a
+// secretbox nonce.
+const (
+	fileKeystoreSaltSize  = 16
+	fileKeystoreNonceSize = 24
+)
+
+// fileKeystoreScryptN, fileKeystoreScryptR and fileKeystoreScryptP are the
+// scrypt cost parameters used to derive the secretbox key from a
+// passphrase.
+const (
+	fileKeystoreScryptN = 1 << 15
+	fileKeystoreScryptR = 8
+	fileKeystoreScryptP = 1
+)
+
+// fileKeystoreSigner is a Signer backed by an ed25519 private key that is
+// kept encrypted on disk and only decrypted into memory for the lifetime of
+// the process.
+type fileKeystoreSigner struct {
+	keyID      string
+	privateKey ed25519.PrivateKey
+}
+
+// EncryptPrivateKeyToFile encrypts privateKey with a key derived from
+// passphrase via scrypt and writes it to path using NaCl secretbox. The file
+// can later be loaded with NewFileKeystoreSigner.
+func EncryptPrivateKeyToFile(path string, privateKey ed25519.PrivateKey, passphrase []byte) error {
+	salt := make([]byte, fileKeystoreSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	var nonce [fileKeystoreNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+
+	key, err := scrypt.Key(passphrase, salt, fileKeystoreScryptN, fileKeystoreScryptR, fileKeystoreScryptP, 32)
+	if err != nil {
+		return err
+	}
+	var secretboxKey [32]byte
+	copy(secretboxKey[:], key)
+
+	sealed := secretbox.Seal(nil, privateKey, &nonce, &secretboxKey)
+
+	out := make([]byte, 0, len(salt)+len(nonce)+len(sealed))
+	out = append(out, salt...)
+	out = append(out, nonce[:]...)
+	out = append(out, sealed...)
+
+	return ioutil.WriteFile(path, out, 0600)
+}
+
+// NewFileKeystoreSigner loads an ed25519 private key that was encrypted with
+// EncryptPrivateKeyToFile and returns a Signer for it.
+func NewFileKeystoreSigner(keyID, path string, passphrase []byte) (Signer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < fileKeystoreSaltSize+fileKeystoreNonceSize {
+		return nil, fmt.Errorf("gomatrixserverlib: keystore file %q is too short", path)
+	}
+
+	salt := data[:fileKeystoreSaltSize]
+	var nonce [fileKeystoreNonceSize]byte
+	copy(nonce[:], data[fileKeystoreSaltSize:fileKeystoreSaltSize+fileKeystoreNonceSize])
+	sealed := data[fileKeystoreSaltSize+fileKeystoreNonceSize:]
+
+	key, err := scrypt.Key(passphrase, salt, fileKeystoreScryptN, fileKeystoreScryptR, fileKeystoreScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	var secretboxKey [32]byte
+	copy(secretboxKey[:], key)
+
+	privateKey, ok := secretbox.Open(nil, sealed, &nonce, &secretboxKey)
+	if !ok {
+		return nil, fmt.Errorf("gomatrixserverlib: could not decrypt keystore file %q: wrong passphrase or corrupt file", path)
+	}
+
+	return &fileKeystoreSigner{keyID: keyID, privateKey: ed25519.PrivateKey(privateKey)}, nil
+}
+
+func (s *fileKeystoreSigner) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, msg), nil
+}
+
+func (s *fileKeystoreSigner) KeyID() string { return s.keyID }
+
+func (s *fileKeystoreSigner) Public() crypto.PublicKey { return s.privateKey.Public() }
@@ -0,0 +1,57 @@
+//go:build gcpkms
+// +build gcpkms
+
+package matrixfederation
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+)
+
+// gcpKMSSigner is a Signer backed by an asymmetric signing key held in
+// Google Cloud KMS. Build with the "gcpkms" tag to use it.
+//
+// As of this writing, Google Cloud KMS does not offer an Ed25519 asymmetric
+// signing algorithm, so the AsymmetricSign call in Sign will be rejected by the
+// service; NewGCPKMSSigner is provided against the day GCP adds support, and
+// is not usable against the real API yet.
+type gcpKMSSigner struct {
+	keyID     string
+	cryptoKey string // full resource name of the KMS CryptoKeyVersion
+	client    *kms.KeyManagementClient
+	publicKey crypto.PublicKey
+}
+
+// NewGCPKMSSigner returns a Signer for the asymmetric-sign CryptoKeyVersion
+// named by cryptoKeyVersion (e.g.
+// "projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1").
+func NewGCPKMSSigner(ctx context.Context, client *kms.KeyManagementClient, keyID, cryptoKeyVersion string) (Signer, error) {
+	resp, err := client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: cryptoKeyVersion})
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := parsePEMPublicKey([]byte(resp.Pem))
+	if err != nil {
+		return nil, err
+	}
+	return &gcpKMSSigner{keyID: keyID, cryptoKey: cryptoKeyVersion, client: client, publicKey: publicKey}, nil
+}
+
+func (s *gcpKMSSigner) Sign(msg []byte) ([]byte, error) {
+	resp, err := s.client.AsymmetricSign(context.Background(), &kmspb.AsymmetricSignRequest{
+		Name: s.cryptoKey,
+		Data: msg,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gomatrixserverlib: GCP KMS AsymmetricSign: %w", err)
+	}
+	return resp.Signature, nil
+}
+
+func (s *gcpKMSSigner) KeyID() string { return s.keyID }
+
+func (s *gcpKMSSigner) Public() crypto.PublicKey { return s.publicKey }
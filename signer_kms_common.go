@@ -0,0 +1,36 @@
+//go:build gcpkms || awskms
+// +build gcpkms awskms
+
+package matrixfederation
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// parsePEMPublicKey decodes a PEM-encoded SubjectPublicKeyInfo, as returned
+// by the GCP KMS GetPublicKey API, into the ed25519 key it contains.
+func parsePEMPublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("gomatrixserverlib: could not decode PEM public key")
+	}
+	return parsePKIXPublicKey(block.Bytes)
+}
+
+// parsePKIXPublicKey decodes a DER-encoded SubjectPublicKeyInfo, as returned
+// by the AWS KMS GetPublicKey API, into the ed25519 key it contains.
+func parsePKIXPublicKey(derBytes []byte) (crypto.PublicKey, error) {
+	publicKey, err := x509.ParsePKIXPublicKey(derBytes)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := publicKey.(ed25519.PublicKey); !ok {
+		return nil, fmt.Errorf("gomatrixserverlib: KMS key is not an ed25519 key")
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,36 @@
+//go:build pkcs11
+// +build pkcs11
+
+package matrixfederation
+
+import (
+	"crypto"
+
+	"github.com/ThalesIgnite/crypto11"
+)
+
+// pkcs11Signer is a Signer backed by a key held in a PKCS#11 token, such as
+// a hardware security module. Build with the "pkcs11" tag and a working
+// PKCS#11 module to use it.
+type pkcs11Signer struct {
+	keyID  string
+	signer crypto11.Signer
+}
+
+// NewPKCS11Signer returns a Signer for the ed25519 key identified by label
+// in the PKCS#11 token accessible through ctx.
+func NewPKCS11Signer(ctx *crypto11.Context, keyID, label string) (Signer, error) {
+	signer, err := ctx.FindKeyPair(nil, []byte(label))
+	if err != nil {
+		return nil, err
+	}
+	return pkcs11Signer{keyID: keyID, signer: signer}, nil
+}
+
+func (s pkcs11Signer) Sign(msg []byte) ([]byte, error) {
+	return s.signer.Sign(nil, msg, crypto.Hash(0))
+}
+
+func (s pkcs11Signer) KeyID() string { return s.keyID }
+
+func (s pkcs11Signer) Public() crypto.PublicKey { return s.signer.Public() }
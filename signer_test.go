@@ -0,0 +1,99 @@
+package matrixfederation
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestEd25519Signer(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := NewEd25519Signer("ed25519:1", privateKey)
+	if signer.KeyID() != "ed25519:1" {
+		t.Fatalf("KeyID() = %q, want %q", signer.KeyID(), "ed25519:1")
+	}
+	if !bytes.Equal(signer.Public().(ed25519.PublicKey), publicKey) {
+		t.Fatalf("Public() did not return the expected public key")
+	}
+
+	message := []byte("this is not JSON, it's a blob")
+	signature, err := signer.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		t.Fatal("signature did not verify against the signer's public key")
+	}
+}
+
+func TestFileKeystoreSigner(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ioutil.TempFile("", "gomatrixserverlib-keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	passphrase := []byte("correct horse battery staple")
+	if err := EncryptPrivateKeyToFile(path, privateKey, passphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewFileKeystoreSigner("ed25519:1", path, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signer.Public().(ed25519.PublicKey), publicKey) {
+		t.Fatalf("Public() did not return the expected public key")
+	}
+
+	message := []byte(`{"this":"is","my":"message"}`)
+	signature, err := signer.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		t.Fatal("signature did not verify against the signer's public key")
+	}
+
+	if _, err := NewFileKeystoreSigner("ed25519:1", path, []byte("wrong passphrase")); err == nil {
+		t.Fatal("expected NewFileKeystoreSigner to fail with the wrong passphrase")
+	}
+}
+
+func TestSignJSONWith(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	entityName := "example.com"
+	keyID := "ed25519:my_key_id"
+	input := []byte(`{"this":"is","my":"message"}`)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := NewEd25519Signer(keyID, privateKey)
+	signed, err := SignJSONWith(entityName, signer, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyJSON(entityName, keyID, publicKey, signed); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,243 @@
+package matrixfederation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+// A Base64String is a string of bytes that is encoded as base64 when encoded
+// as JSON, using the unpadded encoding required by the Matrix specification.
+type Base64String []byte
+
+// Encode encodes the bytes as unpadded base64.
+func (b Base64String) Encode() string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+// Decode decodes a string as unpadded base64.
+func (b *Base64String) Decode(str string) error {
+	result, err := base64.RawStdEncoding.DecodeString(str)
+	if err != nil {
+		return err
+	}
+	*b = Base64String(result)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b Base64String) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Encode())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Base64String) UnmarshalJSON(raw []byte) error {
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return err
+	}
+	return b.Decode(str)
+}
+
+// UnknownKeyError is returned by VerifyJSON if the given key ID is not one of
+// the signatures present on the message.
+type UnknownKeyError struct {
+	KeyID string
+}
+
+func (e UnknownKeyError) Error() string {
+	return fmt.Sprintf("gomatrixserverlib: no signature found for key %q", e.KeyID)
+}
+
+// signableJSON strips the "signatures" and "unsigned" keys from a JSON
+// object and returns the remaining top-level members as a map of raw JSON
+// values, along with whatever those two keys used to contain.
+func signableJSON(message []byte) (content map[string]*json.RawMessage, signatures, unsigned *json.RawMessage, err error) {
+	if err = json.Unmarshal(message, &content); err != nil {
+		return
+	}
+	signatures = content["signatures"]
+	unsigned = content["unsigned"]
+	delete(content, "signatures")
+	delete(content, "unsigned")
+	return
+}
+
+// SignJSON signs a JSON object and adds the signature to the "signatures"
+// member of the object, returning a copy of the input with the signature
+// added. Existing signatures from other entities or key IDs are preserved.
+func SignJSON(signingName, keyID string, privateKey ed25519.PrivateKey, message []byte) ([]byte, error) {
+	return SignJSONWith(signingName, NewEd25519Signer(keyID, privateKey), message)
+}
+
+// SignJSONWith signs a JSON object using signer and adds the signature to
+// the "signatures" member of the object, returning a copy of the input with
+// the signature added. Existing signatures from other entities or key IDs
+// are preserved. Unlike SignJSON, the private key never has to be given to
+// gomatrixserverlib directly: signer may delegate to an HSM or a cloud KMS.
+func SignJSONWith(signingName string, signer Signer, message []byte) ([]byte, error) {
+	content, signatures, unsigned, err := signableJSON(message)
+	if err != nil {
+		return nil, err
+	}
+
+	canonical, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.Sign(canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	signatureMap := map[string]map[string]Base64String{}
+	if signatures != nil {
+		if err = json.Unmarshal(*signatures, &signatureMap); err != nil {
+			return nil, err
+		}
+	}
+
+	entityMap := signatureMap[signingName]
+	if entityMap == nil {
+		entityMap = map[string]Base64String{}
+	}
+	entityMap[signer.KeyID()] = Base64String(signature)
+	signatureMap[signingName] = entityMap
+
+	signatureJSON, err := json.Marshal(signatureMap)
+	if err != nil {
+		return nil, err
+	}
+	rawSignatureJSON := json.RawMessage(signatureJSON)
+	content["signatures"] = &rawSignatureJSON
+	if unsigned != nil {
+		content["unsigned"] = unsigned
+	}
+
+	return json.Marshal(content)
+}
+
+// VerifyJSON checks that the object has a valid signature from the given
+// entity and key ID.
+func VerifyJSON(signingName, keyID string, publicKey ed25519.PublicKey, message []byte) error {
+	signatureMap, canonical, err := verifiableJSON(message)
+	if err != nil {
+		return err
+	}
+	return verifySignature(signatureMap, canonical, signingName, keyID, publicKey)
+}
+
+// verifiableJSON strips the signatures from message and returns the
+// signature map alongside the canonical JSON that was signed.
+func verifiableJSON(message []byte) (signatureMap map[string]map[string]Base64String, canonical []byte, err error) {
+	content, signatures, _, err := signableJSON(message)
+	if err != nil {
+		return nil, nil, err
+	}
+	if signatures == nil {
+		return nil, nil, fmt.Errorf("gomatrixserverlib: message has no signatures")
+	}
+	if err = json.Unmarshal(*signatures, &signatureMap); err != nil {
+		return nil, nil, err
+	}
+	canonical, err = json.Marshal(content)
+	return
+}
+
+// verifySignature checks a single entity/keyID signature against an
+// already-parsed signature map and canonical JSON.
+func verifySignature(signatureMap map[string]map[string]Base64String, canonical []byte, signingName, keyID string, publicKey ed25519.PublicKey) error {
+	entityMap, ok := signatureMap[signingName]
+	if !ok {
+		return fmt.Errorf("gomatrixserverlib: no signatures found for entity %q", signingName)
+	}
+
+	signature, ok := entityMap[keyID]
+	if !ok {
+		return UnknownKeyError{KeyID: keyID}
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("gomatrixserverlib: signature for key %q is %d bytes, expected %d", keyID, len(signature), ed25519.SignatureSize)
+	}
+	if !ed25519.Verify(publicKey, canonical, []byte(signature)) {
+		return fmt.Errorf("gomatrixserverlib: invalid signature for key %q", keyID)
+	}
+	return nil
+}
+
+// AddSignature signs message on behalf of entity/keyID and merges the
+// signature into the object's "signatures" member, leaving any signatures
+// already present from other entities, key IDs, or notaries untouched. It is
+// an alias for SignJSON that makes that intent explicit at call sites where
+// several parties co-sign the same document, such as a server-key response
+// countersigned by multiple notaries.
+func AddSignature(message []byte, signingName, keyID string, privateKey ed25519.PrivateKey) ([]byte, error) {
+	return SignJSON(signingName, keyID, privateKey, message)
+}
+
+// VerifyKey is a trusted entity/key ID pair that VerifyJSONMulti and
+// VerifyJSONThreshold check a message's signatures against.
+type VerifyKey struct {
+	Entity    string
+	KeyID     string
+	PublicKey ed25519.PublicKey
+}
+
+// VerifyResult is the outcome of checking a single VerifyKey against a
+// message in VerifyJSONMulti. Error is nil if and only if the message carries
+// a valid signature from Entity/KeyID.
+type VerifyResult struct {
+	Entity string
+	KeyID  string
+	Error  error
+}
+
+// VerifyJSONMulti checks message against every key in keys, so that a
+// document signed by many entities and key IDs - such as a federation event
+// with signatures from several servers - can be verified in one pass. It
+// returns one VerifyResult per key, in the same order as keys. The returned
+// error is non-nil only if message itself could not be parsed or carries no
+// "signatures" member at all; per-key failures are reported through
+// VerifyResult.Error instead.
+func VerifyJSONMulti(message []byte, keys []VerifyKey) ([]VerifyResult, error) {
+	signatureMap, canonical, err := verifiableJSON(message)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VerifyResult, len(keys))
+	for i, key := range keys {
+		results[i] = VerifyResult{
+			Entity: key.Entity,
+			KeyID:  key.KeyID,
+			Error:  verifySignature(signatureMap, canonical, key.Entity, key.KeyID, key.PublicKey),
+		}
+	}
+	return results, nil
+}
+
+// VerifyJSONThreshold checks message against keys and returns nil only if at
+// least threshold distinct entity/keyID pairs have a valid signature,
+// mirroring the role/threshold key model TUF uses for federation-wide key
+// rotation: a document is trusted once enough of a role's keys have signed
+// it, not because any single one of them did.
+func VerifyJSONThreshold(message []byte, keys []VerifyKey, threshold int) error {
+	results, err := VerifyJSONMulti(message, keys)
+	if err != nil {
+		return err
+	}
+
+	distinct := make(map[string]bool, len(results))
+	for _, result := range results {
+		if result.Error == nil {
+			distinct[result.Entity+" "+result.KeyID] = true
+		}
+	}
+	if len(distinct) < threshold {
+		return fmt.Errorf("gomatrixserverlib: message has %d valid signatures, need at least %d", len(distinct), threshold)
+	}
+	return nil
+}
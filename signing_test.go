@@ -2,6 +2,7 @@ package matrixfederation
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"golang.org/x/crypto/ed25519"
@@ -160,6 +161,70 @@ func TestSignJSONTestVectors(t *testing.T) {
 	}`)
 }
 
+func TestVerifyJSONThreshold(t *testing.T) {
+	entityName := "example.com"
+	input := []byte(`{"this":"is","my":"message"}`)
+
+	publicKey1, privateKey1, err := ed25519.GenerateKey(bytes.NewBuffer([]byte("Some 32 randomly generated bytes")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey2, privateKey2, err := ed25519.GenerateKey(bytes.NewBuffer([]byte("Some other 32 random bytes, too!")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignJSON(entityName, "ed25519:1", privateKey1, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err = AddSignature(signed, entityName, "ed25519:2", privateKey2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []VerifyKey{
+		{Entity: entityName, KeyID: "ed25519:1", PublicKey: publicKey1},
+		{Entity: entityName, KeyID: "ed25519:2", PublicKey: publicKey2},
+		{Entity: entityName, KeyID: "ed25519:3", PublicKey: otherPrivateKey.Public().(ed25519.PublicKey)},
+	}
+
+	results, err := VerifyJSONMulti(signed, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(keys) {
+		t.Fatalf("VerifyJSONMulti returned %d results, want %d", len(results), len(keys))
+	}
+	if results[0].Error != nil || results[1].Error != nil {
+		t.Fatalf("expected keys ed25519:1 and ed25519:2 to verify, got %v, %v", results[0].Error, results[1].Error)
+	}
+	if results[2].Error == nil {
+		t.Fatal("expected key ed25519:3 to fail verification")
+	}
+
+	if err := VerifyJSONThreshold(signed, keys, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyJSONThreshold(signed, keys, 3); err == nil {
+		t.Fatal("expected VerifyJSONThreshold to fail when threshold cannot be met")
+	}
+
+	// A duplicated VerifyKey for the same entity/keyID must not let one
+	// signature count twice towards the threshold.
+	duplicated := []VerifyKey{
+		{Entity: entityName, KeyID: "ed25519:1", PublicKey: publicKey1},
+		{Entity: entityName, KeyID: "ed25519:1", PublicKey: publicKey1},
+	}
+	if err := VerifyJSONThreshold(signed, duplicated, 2); err == nil {
+		t.Fatal("expected VerifyJSONThreshold to fail when threshold 2 is met only by a duplicated key")
+	}
+}
+
 type MyMessage struct {
 	Unsigned   *json.RawMessage `json:"unsigned"`
 	Content    *json.RawMessage `json:"content"`
@@ -0,0 +1,76 @@
+package matrixfederation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+	"gopkg.in/yaml.v2"
+)
+
+// MarshalYAML implements yaml.Marshaler, encoding the bytes the same way
+// MarshalJSON does: as unpadded base64.
+func (b Base64String) MarshalYAML() (interface{}, error) {
+	return b.Encode(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (b *Base64String) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	return b.Decode(str)
+}
+
+// yamlToJSON re-encodes a YAML document as the equivalent JSON, so that it
+// can be run through the ordinary canonical-JSON signing path.
+func yamlToJSON(yamlBytes []byte) ([]byte, error) {
+	var tree interface{}
+	if err := yaml.Unmarshal(yamlBytes, &tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalizeGenericValue(tree))
+}
+
+// jsonToYAML re-encodes a JSON document as the equivalent YAML. Numbers are
+// decoded via decodeJSONGeneric so that integers outside float64's 53-bit
+// mantissa survive the round trip.
+func jsonToYAML(jsonBytes []byte) ([]byte, error) {
+	tree, err := decodeJSONGeneric(jsonBytes)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tree)
+}
+
+// SignYAML signs a YAML document the same way SignJSON signs a JSON one -
+// useful for config-file consumers, such as a Dendrite-style server_key.yaml,
+// that want the same signing primitives without hand-rolling the conversion
+// to and from JSON. The document is converted to canonical JSON, signed
+// there, and the result converted back to YAML, so the signature is
+// identical to the one SignJSON would have produced for the logical
+// equivalent JSON document. Integers are preserved exactly as long as they
+// fit in an int64; anything wider round-trips through float64 like any other
+// JSON number outside that range.
+func SignYAML(signingName, keyID string, privateKey ed25519.PrivateKey, message []byte) ([]byte, error) {
+	jsonMessage, err := yamlToJSON(message)
+	if err != nil {
+		return nil, fmt.Errorf("gomatrixserverlib: could not convert YAML to JSON: %w", err)
+	}
+	signed, err := SignJSON(signingName, keyID, privateKey, jsonMessage)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(signed)
+}
+
+// VerifyYAML checks a YAML document's signature the same way VerifyJSON
+// checks a JSON one, by converting it to its canonical JSON form first.
+func VerifyYAML(signingName, keyID string, publicKey ed25519.PublicKey, message []byte) error {
+	jsonMessage, err := yamlToJSON(message)
+	if err != nil {
+		return fmt.Errorf("gomatrixserverlib: could not convert YAML to JSON: %w", err)
+	}
+	return VerifyJSON(signingName, keyID, publicKey, jsonMessage)
+}
@@ -0,0 +1,81 @@
+package matrixfederation
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestSignAndVerifyYAML(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	entityName := "example.com"
+	keyID := "ed25519:my_key_id"
+	input := []byte("this: is\nmy: message\n")
+
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignYAML(entityName, keyID, privateKey, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyYAML(entityName, keyID, publicKey, signed); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonForm, err := yamlToJSON(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyJSON(entityName, keyID, publicKey, jsonForm); err != nil {
+		t.Fatalf("YAML signature did not verify as JSON: %v", err)
+	}
+}
+
+func TestSignAndVerifyYAMLLargeInteger(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	entityName := "example.com"
+	keyID := "ed25519:my_key_id"
+	// 2^53 + 1: the smallest integer a float64 cannot represent exactly.
+	input := []byte("big: 9007199254740993\n")
+
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignYAML(entityName, keyID, privateKey, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyYAML(entityName, keyID, publicKey, signed); err != nil {
+		t.Fatalf("VerifyYAML failed for a freshly signed document: %v", err)
+	}
+}
+
+func TestVerifyYAMLTamperedPayload(t *testing.T) {
+	random := bytes.NewBuffer([]byte("Some 32 randomly generated bytes"))
+	entityName := "example.com"
+	keyID := "ed25519:my_key_id"
+	input := []byte("this: is\nmy: message\n")
+
+	publicKey, privateKey, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignYAML(entityName, keyID, privateKey, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := append(bytes.TrimRight(signed, "\n"), []byte("\nextra: field\n")...)
+	if err := VerifyYAML(entityName, keyID, publicKey, tampered); err == nil {
+		t.Fatal("expected VerifyYAML to fail for a tampered document")
+	}
+}